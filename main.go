@@ -1,23 +1,63 @@
 package main
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"mime"
+	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
+	"unicode/utf8"
 )
 
 // Options for the program
 type Options struct {
-	rootDir       string
-	includeExts   []string
-	excludeExts   []string
-	structureFile string
-	contentFile   string
-	summaryFile   string
+	rootDir         string
+	includeExts     []string
+	excludeExts     []string
+	includePatterns []string
+	excludePatterns []string
+	structureFile   string
+	contentFile     string
+	summaryFile     string
+	format          string
+	jobs            int
+	indexFile       string
+	sinceFile       string
+	tokenizerName   string
+	tokenizerVocab  string
+	serveAddr       string
+
+	// Tokenizer counts tokens for Statistics and the index; built from
+	// tokenizerName/tokenizerVocab once in main so every file reuses it.
+	Tokenizer Tokenizer
+
+	// Error is called whenever the scanner can't stat or read a file. A nil
+	// return skips the file and continues scanning; a non-nil return aborts
+	// the whole scan with that error (the Archiver convention for plugging
+	// in an abort-on-first-error vs. skip-and-continue policy).
+	Error func(path string, fi os.FileInfo, err error) error
+
+	// Progress, if set, is called after every file the scanner finishes
+	// reading with the running totals, so callers can render a live status
+	// line while large trees are scanned.
+	Progress func(filesScanned int, bytesRead int64)
 }
 
 // Main function
@@ -26,20 +66,70 @@ func main() {
 	rootDir := flag.String("dir", ".", "Root directory to analyze")
 	includeExtsStr := flag.String("include", "md,go,mbt", "Extensions to include (comma separated)")
 	excludeExtsStr := flag.String("exclude", "html,css", "Extensions to exclude (comma separated)")
+	includePatternsStr := flag.String("include-pattern", "", "Gitignore-style glob patterns to include (comma separated, e.g. src/**/*.go,docs/**/*.md)")
+	excludePatternsStr := flag.String("exclude-pattern", "", "Gitignore-style glob patterns to exclude (comma separated, e.g. vendor/**,*_test.go)")
 	structureFile := flag.String("structure", "directory-structure.txt", "Output file for directory structure")
 	contentFile := flag.String("content", "content.txt", "Output file for file contents")
 	summaryFile := flag.String("summary", "summary.txt", "Output file for statistics summary")
+	format := flag.String("format", "", "Content output format: text, zip, tar, tar.gz, tar.bz2 (default: guessed from -content extension)")
+	jobs := flag.Int("jobs", 4, "Number of concurrent workers scanning and reading files")
+	onError := flag.String("on-error", "abort", "Error policy when a file can't be read: abort or skip")
+	showProgress := flag.Bool("progress", false, "Print a live progress line while scanning")
+	indexFile := flag.String("index", ".packfiles-index.json", "Path to write this run's index file to, for future -since comparisons")
+	sinceFile := flag.String("since", "", "Path to a previous index file: unchanged files skip re-hashing/re-tokenizing and a diff is added to the summary")
+	tokenizerName := flag.String("tokenizer", "words", "Token counting backend: words, chars, bytes, tiktoken-cl100k or tiktoken-o200k")
+	tokenizerVocab := flag.String("tokenizer-vocab", "", "Merges file for the tiktoken-cl100k/tiktoken-o200k tokenizers (a custom byte-pair approximation, not an OpenAI-compatible vocab)")
+	serveAddr := flag.String("serve", "", "Address to serve a live, browsable view of the pack on (e.g. :8080); runs instead of a one-shot pack")
 
 	flag.Parse()
 
 	// Preparing options
 	options := Options{
-		rootDir:       *rootDir,
-		includeExts:   normalizeExtensions(strings.Split(*includeExtsStr, ",")),
-		excludeExts:   normalizeExtensions(strings.Split(*excludeExtsStr, ",")),
-		structureFile: *structureFile,
-		contentFile:   *contentFile,
-		summaryFile:   *summaryFile,
+		rootDir:         *rootDir,
+		includeExts:     normalizeExtensions(strings.Split(*includeExtsStr, ",")),
+		excludeExts:     normalizeExtensions(strings.Split(*excludeExtsStr, ",")),
+		includePatterns: normalizePatterns(strings.Split(*includePatternsStr, ",")),
+		excludePatterns: normalizePatterns(strings.Split(*excludePatternsStr, ",")),
+		structureFile:   *structureFile,
+		contentFile:     *contentFile,
+		summaryFile:     *summaryFile,
+		format:          *format,
+		jobs:            *jobs,
+		indexFile:       *indexFile,
+		sinceFile:       *sinceFile,
+		tokenizerName:   *tokenizerName,
+		tokenizerVocab:  *tokenizerVocab,
+		serveAddr:       *serveAddr,
+	}
+
+	if options.format == "" {
+		options.format = formatFromExtension(options.contentFile)
+	}
+
+	tokenizer, err := newTokenizer(options.tokenizerName, options.tokenizerVocab)
+	if err != nil {
+		fmt.Printf("Error creating tokenizer: %v\n", err)
+		os.Exit(1)
+	}
+	options.Tokenizer = tokenizer
+
+	switch *onError {
+	case "abort":
+		options.Error = nil
+	case "skip":
+		options.Error = func(path string, fi os.FileInfo, err error) error {
+			fmt.Printf("Warning: skipping %s: %v\n", path, err)
+			return nil
+		}
+	default:
+		fmt.Printf("Unknown -on-error value %q (expected abort or skip)\n", *onError)
+		os.Exit(1)
+	}
+
+	if *showProgress {
+		options.Progress = func(filesScanned int, bytesRead int64) {
+			fmt.Printf("\rScanning... %d files, %.2f KB read", filesScanned, float64(bytesRead)/1024.0)
+		}
 	}
 
 	// Check if root directory exists
@@ -52,12 +142,36 @@ func main() {
 	fmt.Printf("Analyzing directory: %s\n", options.rootDir)
 	fmt.Printf("Included extensions: %v\n", options.includeExts)
 	fmt.Printf("Excluded extensions: %v\n", options.excludeExts)
+	if len(options.includePatterns) > 0 {
+		fmt.Printf("Include patterns: %v\n", options.includePatterns)
+	}
+	if len(options.excludePatterns) > 0 {
+		fmt.Printf("Exclude patterns: %v\n", options.excludePatterns)
+	}
 	fmt.Printf("Structure file: %s\n", options.structureFile)
 	fmt.Printf("Content file: %s\n", options.contentFile)
+	fmt.Printf("Content format: %s\n", options.format)
 	fmt.Printf("Summary file: %s\n", options.summaryFile)
+	fmt.Printf("Workers: %d\n", options.jobs)
+	fmt.Printf("Tokenizer: %s\n", options.tokenizerName)
+	fmt.Printf("Index file: %s\n", options.indexFile)
+	if options.sinceFile != "" {
+		fmt.Printf("Since index: %s\n", options.sinceFile)
+	}
+
+	if options.serveAddr != "" {
+		if err := serve(options); err != nil {
+			fmt.Printf("Error serving: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	// Analyze directory
 	files, err := walkDirectory(options)
+	if *showProgress {
+		fmt.Println()
+	}
 	if err != nil {
 		fmt.Printf("Error analyzing directory: %v\n", err)
 		os.Exit(1)
@@ -69,6 +183,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Compare against the previous index (if any), tagging each file with a
+	// hash and token count - reused from the index when size/mtime didn't
+	// change - and write the new index for the next run to compare against.
+	diff, err := updateIndex(files, options)
+	if err != nil {
+		fmt.Printf("Error updating index: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Collect statistics while generating content file
 	stats, err := generateContentFile(files, options)
 	if err != nil {
@@ -77,7 +200,7 @@ func main() {
 	}
 
 	// Generate summary file
-	if err := generateSummaryFile(stats, options); err != nil {
+	if err := generateSummaryFile(stats, options, diff); err != nil {
 		fmt.Printf("Error generating summary file: %v\n", err)
 		os.Exit(1)
 	}
@@ -110,13 +233,30 @@ func normalizeExtensions(exts []string) []string {
 	return result
 }
 
+// Normalize a list of comma-split glob patterns, trimming whitespace and
+// dropping empty entries
+func normalizePatterns(patterns []string) []string {
+	result := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		result = append(result, pattern)
+	}
+	return result
+}
+
 // Structure to represent a file or directory
 type FileInfo struct {
 	Path     string
 	IsDir    bool
 	Children []*FileInfo
-	Size     int64  // File size in bytes
-	Content  string // File content (used for token counting)
+	Size     int64     // File size in bytes
+	ModTime  time.Time // Last modification time, for index change-detection
+	Content  string    // File content (used for token counting)
+	Hash     string    // sha256 of Content, cached via the index when unchanged
+	Tokens   int       // Token count, cached via the index when unchanged
 }
 
 // Structure to store statistics
@@ -126,179 +266,756 @@ type Statistics struct {
 	AverageFileSize float64 // Average file size in bytes
 	TotalTokens     int     // Total number of tokens
 	AverageTokens   float64 // Average number of tokens per file
+
+	TokensByExt  map[string]int // Token total per file extension
+	TokensByFile map[string]int // Token total per file path
+}
+
+// candidate is a file path the scanner goroutine has decided to include,
+// still waiting for a worker to read its content.
+type candidate struct {
+	index int // position in filepath.Walk's (lexical) visit order
+	path  string
+	info  os.FileInfo
+}
+
+// scanResult is what a worker hands back to the collector for one candidate.
+type scanResult struct {
+	index int
+	file  *FileInfo
+	err   error
 }
 
-// Recursively walk through the directory
+// walkDirectory scans the directory tree in a small pipeline: this goroutine
+// emits candidate paths from filepath.Walk, a pool of options.jobs worker
+// goroutines stat and read file contents in parallel, and this function
+// collects the results back into the same order filepath.Walk produced them
+// in, so output stays deterministic regardless of how workers interleave.
 func walkDirectory(options Options) ([]*FileInfo, error) {
-	var filteredFiles []*FileInfo
+	jobs := options.jobs
+	if jobs < 1 {
+		jobs = 1
+	}
 
-	// Recursive walk function
-	err := filepath.Walk(options.rootDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	// Walk the resolved absolute root rather than options.rootDir verbatim,
+	// so FileInfo.Path always lands in the same coordinate space as the
+	// rootPath resolveRootPath hands out elsewhere (archivePath, the
+	// structure tree, the HTTP server) - otherwise filepath.Rel between an
+	// absolute rootPath and a relative FileInfo.Path always fails.
+	rootPath, err := resolveRootPath(options.rootDir)
+	if err != nil {
+		return nil, err
+	}
+	options.rootDir = rootPath
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	candidates := make(chan candidate)
+	results := make(chan scanResult)
+
+	var walkErr error
+	walkDone := make(chan struct{})
+	go func() {
+		walkErr = scanCandidates(ctx, options, candidates)
+		close(walkDone)
+	}()
+
+	var workers sync.WaitGroup
+	workers.Add(jobs)
+	for i := 0; i < jobs; i++ {
+		go func() {
+			defer workers.Done()
+			readCandidates(ctx, candidates, results, options)
+		}()
+	}
+
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	collected := make(map[int]*FileInfo)
+	maxIndex := -1
+	var filesScanned int
+	var bytesRead int64
+	var firstErr error
+
+	for res := range results {
+		if res.index > maxIndex {
+			maxIndex = res.index
 		}
 
-		// If it's a file, check its extension
-		if !info.IsDir() {
-			ext := filepath.Ext(path)
-			
-			// Check if file should be included based on extensions
-			includeFile := false
-			if len(options.includeExts) == 0 {
-				includeFile = true // If no extensions specified, include all
-			} else {
-				for _, includeExt := range options.includeExts {
-					if strings.EqualFold(ext, includeExt) {
-						includeFile = true
-						break
-					}
-				}
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = res.err
+				cancel()
 			}
+			continue
+		}
+
+		if res.file == nil {
+			continue // skipped by the Error hook
+		}
+
+		collected[res.index] = res.file
+		filesScanned++
+		bytesRead += res.file.Size
+		if options.Progress != nil {
+			options.Progress(filesScanned, bytesRead)
+		}
+	}
+
+	<-walkDone
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if walkErr != nil {
+		return nil, walkErr
+	}
+
+	filteredFiles := make([]*FileInfo, 0, len(collected))
+	for i := 0; i <= maxIndex; i++ {
+		if file, ok := collected[i]; ok {
+			filteredFiles = append(filteredFiles, file)
+		}
+	}
+
+	return filteredFiles, nil
+}
+
+// scanCandidates walks the tree and feeds matching file paths to candidates,
+// applying the same extension/pattern filters and directory pruning as
+// before. It honors options.Error for any walk-time error (permission
+// denied, broken symlink, ...) and stops early once ctx is cancelled.
+func scanCandidates(ctx context.Context, options Options, candidates chan<- candidate) error {
+	defer close(candidates)
 
-			// Check if file should be excluded based on extensions
-			for _, excludeExt := range options.excludeExts {
-				if strings.EqualFold(ext, excludeExt) {
-					includeFile = false
-					break
+	index := 0
+
+	return filepath.Walk(options.rootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if options.Error != nil {
+				if herr := options.Error(path, info, err); herr != nil {
+					return herr
 				}
+				if info != nil && info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
 			}
+			return err
+		}
 
-			// If file should be included, add it to the list
-			if includeFile {
-				// Read file content for statistics
-				content, err := os.ReadFile(path)
-				if err != nil {
-					return fmt.Errorf("unable to read file %s: %v", path, err)
-				}
-				
-				filteredFiles = append(filteredFiles, &FileInfo{
-					Path:    path,
-					IsDir:   false,
-					Size:    info.Size(),
-					Content: string(content),
-				})
+		select {
+		case <-ctx.Done():
+			return filepath.SkipAll
+		default:
+		}
+
+		// Nothing to filter on the root itself
+		if path == options.rootDir {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(options.rootDir, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		// Directories are only ever pruned by include patterns: if no
+		// include pattern could possibly match anything under this
+		// prefix, skip walking into it entirely.
+		if info.IsDir() {
+			if shouldPruneDir(relPath, options.includePatterns) {
+				return filepath.SkipDir
 			}
+			return nil
+		}
+
+		if !matchesExtensions(path, options) || !matchesPatterns(relPath, options) {
+			return nil
+		}
+
+		select {
+		case candidates <- candidate{index: index, path: path, info: info}:
+			index++
+		case <-ctx.Done():
+			return filepath.SkipAll
 		}
 
 		return nil
 	})
+}
+
+// readCandidates is run by each worker goroutine: it stats nothing further
+// (filepath.Walk already did that) and just reads file content, reporting
+// either a FileInfo or, through options.Error, an abort or a skip.
+func readCandidates(ctx context.Context, candidates <-chan candidate, results chan<- scanResult, options Options) {
+	for c := range candidates {
+		select {
+		case <-ctx.Done():
+			continue
+		default:
+		}
+
+		content, err := os.ReadFile(c.path)
+		if err != nil {
+			readErr := fmt.Errorf("unable to read file %s: %v", c.path, err)
+			if options.Error != nil {
+				if herr := options.Error(c.path, c.info, err); herr != nil {
+					sendResult(ctx, results, scanResult{index: c.index, err: herr})
+				} else {
+					sendResult(ctx, results, scanResult{index: c.index})
+				}
+			} else {
+				sendResult(ctx, results, scanResult{index: c.index, err: readErr})
+			}
+			continue
+		}
+
+		sendResult(ctx, results, scanResult{
+			index: c.index,
+			file: &FileInfo{
+				Path:    c.path,
+				IsDir:   false,
+				Size:    c.info.Size(),
+				ModTime: c.info.ModTime(),
+				Content: string(content),
+			},
+		})
+	}
+}
+
+// sendResult delivers res unless ctx is already cancelled, so a worker
+// never blocks forever on a collector that has stopped reading after an
+// abort.
+func sendResult(ctx context.Context, results chan<- scanResult, res scanResult) {
+	select {
+	case results <- res:
+	case <-ctx.Done():
+	}
+}
+
+// matchesExtensions applies the original include/exclude-by-extension rules.
+func matchesExtensions(path string, options Options) bool {
+	ext := filepath.Ext(path)
+
+	// Check if file should be included based on extensions
+	includeFile := false
+	if len(options.includeExts) == 0 {
+		includeFile = true // If no extensions specified, include all
+	} else {
+		for _, includeExt := range options.includeExts {
+			if strings.EqualFold(ext, includeExt) {
+				includeFile = true
+				break
+			}
+		}
+	}
+
+	// Check if file should be excluded based on extensions
+	for _, excludeExt := range options.excludeExts {
+		if strings.EqualFold(ext, excludeExt) {
+			includeFile = false
+			break
+		}
+	}
+
+	return includeFile
+}
+
+// matchesPatterns applies the gitignore-style include/exclude glob patterns
+// on top of the extension filter. An empty includePatterns list includes
+// everything the extension filter let through; a non-empty one requires at
+// least one full match.
+func matchesPatterns(relPath string, options Options) bool {
+	if matchesAnyPattern(options.excludePatterns, relPath) {
+		return false
+	}
+
+	if len(options.includePatterns) == 0 {
+		return true
+	}
+
+	return matchesAnyPattern(options.includePatterns, relPath)
+}
+
+// matchesAnyPattern reports whether relPath fully matches at least one of patterns.
+func matchesAnyPattern(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := matchPattern(pattern, relPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldPruneDir reports whether a directory can be skipped entirely: true
+// only when include patterns are set and none of them could match anything
+// under relPath, so filepath.Walk never descends into it.
+func shouldPruneDir(relPath string, includePatterns []string) bool {
+	if len(includePatterns) == 0 {
+		return false
+	}
+
+	for _, pattern := range includePatterns {
+		if matched, partial := matchPattern(pattern, relPath); matched || partial {
+			return false
+		}
+	}
+
+	return true
+}
 
-	return filteredFiles, err
+// matchPattern reports whether relPath matches a gitignore-style glob
+// pattern (path components separated by "/", "*" matching within a
+// component, "**" matching zero or more whole components). It also reports
+// whether relPath is merely a "partial" match: too shallow to satisfy
+// pattern yet, but potentially a prefix of something that would. That
+// distinction lets the walker prune directories that can never lead to a
+// match instead of inspecting every file beneath them.
+func matchPattern(pattern, relPath string) (matched bool, partial bool) {
+	// A pattern with no "/" (e.g. "*_test.go") is a bare gitignore-style
+	// name rule: it matches the basename at any depth, so it can never
+	// rule out a directory during pruning.
+	if !strings.Contains(pattern, "/") {
+		ok, err := filepath.Match(pattern, filepath.Base(relPath))
+		if err != nil {
+			return false, false
+		}
+		return ok, !ok
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(relPath, "/"))
+}
+
+// matchSegments matches pattern path components against relPath components,
+// letting a "**" component consume zero or more of them.
+func matchSegments(pattern, path []string) (matched bool, partial bool) {
+	if len(pattern) == 0 {
+		return len(path) == 0, false
+	}
+
+	if pattern[0] == "**" {
+		if len(pattern) == 1 {
+			return true, false
+		}
+		if m, p := matchSegments(pattern[1:], path); m || p {
+			return m, p
+		}
+		if len(path) == 0 {
+			return false, true
+		}
+		return matchSegments(pattern, path[1:])
+	}
+
+	if len(path) == 0 {
+		// relPath ran out but pattern hasn't: it's a directory prefix
+		// that could still be walked into a full match.
+		return false, true
+	}
+
+	ok, err := filepath.Match(pattern[0], path[0])
+	if err != nil || !ok {
+		return false, false
+	}
+	return matchSegments(pattern[1:], path[1:])
 }
 
 // Generate directory structure file with filtered files
 func generateStructureFile(files []*FileInfo, options Options) error {
-	// Create structure file
-	file, err := os.Create(options.structureFile)
+	tree, err := buildStructureTree(files, options)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+
+	return os.WriteFile(options.structureFile, []byte(tree), 0644)
+}
+
+// buildStructureTree renders the directory tree of filtered files as text,
+// shared by generateStructureFile and the "/structure" HTTP route.
+func buildStructureTree(files []*FileInfo, options Options) (string, error) {
+	var out strings.Builder
 
 	// Title
-	fmt.Fprintln(file, "Directory structure:")
+	fmt.Fprintln(&out, "Directory structure:")
 
 	// Structure to keep track of processed directories
 	dirs := make(map[string]bool)
-	
+
 	// Sort files by path for cleaner display
 	sort.Slice(files, func(i, j int) bool {
 		return files[i].Path < files[j].Path
 	})
 
 	// Create tree structure
-	rootPath := options.rootDir
-	if rootPath == "." {
-		var err error
-		rootPath, err = os.Getwd()
-		if err != nil {
-			return err
-		}
+	rootPath, err := resolveRootPath(options.rootDir)
+	if err != nil {
+		return "", err
 	}
 
 	rootName := filepath.Base(rootPath)
-	fmt.Fprintf(file, "└── %s/\n", rootName)
+	fmt.Fprintf(&out, "└── %s/\n", rootName)
 
 	// For each filtered file
 	for _, fileInfo := range files {
 		// Relative path from root directory
 		relPath, err := filepath.Rel(rootPath, fileInfo.Path)
 		if err != nil {
-			return err
+			return "", err
 		}
 
 		// Split path into components
 		components := strings.Split(relPath, string(filepath.Separator))
-		
+
 		// Display tree structure for this file
 		for i := 0; i < len(components); i++ {
 			// Build path up to this level
 			currentPath := filepath.Join(components[:i+1]...)
-			
+
 			// Check if it's a directory or file
 			isLastComponent := i == len(components)-1
 			isDir := !isLastComponent
-			
+
 			// If it's a directory that hasn't been displayed
 			if isDir && !dirs[currentPath] {
 				dirs[currentPath] = true
 				prefix := strings.Repeat("│   ", i) + "├── "
-				fmt.Fprintf(file, "    %s%s/\n", prefix, components[i])
+				fmt.Fprintf(&out, "    %s%s/\n", prefix, components[i])
 			} else if isLastComponent {
 				// If it's the final file
 				prefix := strings.Repeat("│   ", i) + "├── "
-				fmt.Fprintf(file, "    %s%s\n", prefix, components[i])
+				fmt.Fprintf(&out, "    %s%s\n", prefix, components[i])
 			}
 		}
 	}
 
-	return nil
+	return out.String(), nil
 }
 
-// Generate content file with all filtered files and collect statistics
-func generateContentFile(files []*FileInfo, options Options) (Statistics, error) {
-	// Create content file
-	contentFile, err := os.Create(options.contentFile)
+// Generate content output with all filtered files and collect statistics.
+// The actual packing (flat text dump vs. a real archive) is delegated to a
+// ContentWriter so a single walk over files works regardless of format.
+func generateContentFile(files []*FileInfo, options Options) (stats Statistics, err error) {
+	writer, err := newContentWriter(options)
 	if err != nil {
 		return Statistics{}, err
 	}
-	defer contentFile.Close()
+	defer func() {
+		if cerr := writer.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	for i, fileInfo := range files {
+		if err = writer.WriteFile(i, fileInfo); err != nil {
+			return Statistics{}, err
+		}
+	}
+
+	return computeStatistics(files), nil
+}
 
-	// Initialize statistics
+// computeStatistics aggregates size and token totals across files. Each
+// fileInfo must already have Tokens populated (e.g. via updateIndex).
+func computeStatistics(files []*FileInfo) Statistics {
 	stats := Statistics{
-		TotalFiles: len(files),
+		TotalFiles:   len(files),
+		TokensByExt:  make(map[string]int),
+		TokensByFile: make(map[string]int),
 	}
 
-	// For each filtered file
-	for i, fileInfo := range files {
-		// Separator
-		if i > 0 {
-			fmt.Fprintln(contentFile)
-		}
-		
-		// Header for the file
-		fmt.Fprintln(contentFile, "================================================")
-		fmt.Fprintf(contentFile, "File %d: %s\n", i+1, fileInfo.Path)
-		fmt.Fprintln(contentFile, "================================================")
-		
-		// Write content
-		fmt.Fprintln(contentFile, fileInfo.Content)
-		
-		// Collect statistics
+	for _, fileInfo := range files {
 		stats.TotalSize += fileInfo.Size
-		
-		// Count tokens (words) in content
-		tokens := countTokens(fileInfo.Content)
-		stats.TotalTokens += tokens
+		stats.TotalTokens += fileInfo.Tokens
+
+		ext := filepath.Ext(fileInfo.Path)
+		if ext == "" {
+			ext = "(none)"
+		}
+		stats.TokensByExt[ext] += fileInfo.Tokens
+		stats.TokensByFile[fileInfo.Path] = fileInfo.Tokens
 	}
 
-	// Calculate averages
 	if stats.TotalFiles > 0 {
 		stats.AverageFileSize = float64(stats.TotalSize) / float64(stats.TotalFiles)
 		stats.AverageTokens = float64(stats.TotalTokens) / float64(stats.TotalFiles)
 	}
 
-	return stats, nil
+	return stats
+}
+
+// formatFromExtension guesses the content format from the output file name.
+func formatFromExtension(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	switch ext {
+	case ".zip":
+		return "zip"
+	case ".tar":
+		return "tar"
+	case ".gz":
+		// No plain-gzip ContentWriter exists, so any ".gz" (".tar.gz" or
+		// bare) is treated as "tar.gz".
+		return "tar.gz"
+	case ".bz2":
+		return "tar.bz2"
+	default:
+		return "text"
+	}
+}
+
+// ContentWriter packs the filtered files into the configured content output.
+// Implementations decide whether that output is the flat text dump or a real
+// archive preserving each file's path relative to options.rootDir.
+type ContentWriter interface {
+	// WriteFile appends one file (index is its 0-based position in the walk).
+	WriteFile(index int, fileInfo *FileInfo) error
+	// Close flushes and closes the underlying output, returning any error.
+	Close() error
+}
+
+// newContentWriter creates the ContentWriter matching options.format.
+func newContentWriter(options Options) (ContentWriter, error) {
+	rootPath, err := resolveRootPath(options.rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(options.contentFile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch options.format {
+	case "zip":
+		return newZipContentWriter(file, rootPath), nil
+	case "tar":
+		return newTarContentWriter(file, tar.NewWriter(file), rootPath), nil
+	case "tar.gz":
+		gzWriter := gzip.NewWriter(file)
+		return newTarGzContentWriter(file, gzWriter, tar.NewWriter(gzWriter), rootPath), nil
+	case "tar.bz2":
+		return newTarBz2ContentWriter(file, rootPath)
+	case "text", "":
+		return newTextContentWriter(file), nil
+	default:
+		file.Close()
+		return nil, fmt.Errorf("unsupported content format: %s", options.format)
+	}
+}
+
+// resolveRootPath turns the configured root directory into an absolute path,
+// resolving "." to the current working directory (same rule generateStructureFile
+// uses when it needs an absolute path to compute relative paths from).
+func resolveRootPath(rootDir string) (string, error) {
+	return filepath.Abs(rootDir)
+}
+
+// archivePath returns fileInfo's path relative to rootPath, using forward
+// slashes as required by the zip and tar formats.
+func archivePath(rootPath string, fileInfo *FileInfo) (string, error) {
+	relPath, err := filepath.Rel(rootPath, fileInfo.Path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(relPath), nil
+}
+
+// textContentWriter reproduces the original flat content.txt layout.
+type textContentWriter struct {
+	file  *os.File
+	count int
+}
+
+func newTextContentWriter(file *os.File) *textContentWriter {
+	return &textContentWriter{file: file}
+}
+
+func (w *textContentWriter) WriteFile(index int, fileInfo *FileInfo) error {
+	if w.count > 0 {
+		fmt.Fprintln(w.file)
+	}
+	w.count++
+
+	fmt.Fprintln(w.file, "================================================")
+	fmt.Fprintf(w.file, "File %d: %s\n", index+1, fileInfo.Path)
+	fmt.Fprintln(w.file, "================================================")
+	fmt.Fprintln(w.file, fileInfo.Content)
+
+	return nil
+}
+
+func (w *textContentWriter) Close() error {
+	return w.file.Close()
+}
+
+// zipContentWriter packs files into a zip archive, preserving relative paths.
+type zipContentWriter struct {
+	file     *os.File
+	zw       *zip.Writer
+	rootPath string
+}
+
+func newZipContentWriter(file *os.File, rootPath string) *zipContentWriter {
+	return &zipContentWriter{file: file, zw: zip.NewWriter(file), rootPath: rootPath}
+}
+
+func (w *zipContentWriter) WriteFile(index int, fileInfo *FileInfo) error {
+	name, err := archivePath(w.rootPath, fileInfo)
+	if err != nil {
+		return err
+	}
+
+	entry, err := w.zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = entry.Write([]byte(fileInfo.Content))
+	return err
+}
+
+func (w *zipContentWriter) Close() error {
+	if err := w.zw.Close(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// tarContentWriter packs files into an uncompressed tar archive.
+type tarContentWriter struct {
+	file     *os.File
+	tw       *tar.Writer
+	rootPath string
+}
+
+func newTarContentWriter(file *os.File, tw *tar.Writer, rootPath string) *tarContentWriter {
+	return &tarContentWriter{file: file, tw: tw, rootPath: rootPath}
+}
+
+func (w *tarContentWriter) WriteFile(index int, fileInfo *FileInfo) error {
+	return writeTarEntry(w.tw, w.rootPath, fileInfo)
+}
+
+func (w *tarContentWriter) Close() error {
+	if err := w.tw.Close(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// tarGzContentWriter packs files into a gzip-compressed tar archive (.tar.gz).
+type tarGzContentWriter struct {
+	file     *os.File
+	gz       *gzip.Writer
+	tw       *tar.Writer
+	rootPath string
+}
+
+func newTarGzContentWriter(file *os.File, gz *gzip.Writer, tw *tar.Writer, rootPath string) *tarGzContentWriter {
+	return &tarGzContentWriter{file: file, gz: gz, tw: tw, rootPath: rootPath}
+}
+
+func (w *tarGzContentWriter) WriteFile(index int, fileInfo *FileInfo) error {
+	return writeTarEntry(w.tw, w.rootPath, fileInfo)
+}
+
+func (w *tarGzContentWriter) Close() error {
+	if err := w.tw.Close(); err != nil {
+		w.gz.Close()
+		w.file.Close()
+		return err
+	}
+	if err := w.gz.Close(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// tarBz2ContentWriter packs files into a bzip2-compressed tar archive
+// (.tar.bz2). The standard library only ships a bzip2 reader, so the
+// compression itself is delegated to the system "bzip2" binary, fed through
+// a pipe the same way os/exec wires up any external filter command.
+type tarBz2ContentWriter struct {
+	file     *os.File
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	tw       *tar.Writer
+	rootPath string
+}
+
+func newTarBz2ContentWriter(file *os.File, rootPath string) (*tarBz2ContentWriter, error) {
+	cmd := exec.Command("bzip2", "-c")
+	cmd.Stdout = file
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("unable to start bzip2: %v", err)
+	}
+
+	return &tarBz2ContentWriter{
+		file:     file,
+		cmd:      cmd,
+		stdin:    stdin,
+		tw:       tar.NewWriter(stdin),
+		rootPath: rootPath,
+	}, nil
+}
+
+func (w *tarBz2ContentWriter) WriteFile(index int, fileInfo *FileInfo) error {
+	return writeTarEntry(w.tw, w.rootPath, fileInfo)
+}
+
+func (w *tarBz2ContentWriter) Close() error {
+	tarErr := w.tw.Close()
+	stdinErr := w.stdin.Close()
+	waitErr := w.cmd.Wait()
+	fileErr := w.file.Close()
+
+	switch {
+	case tarErr != nil:
+		return tarErr
+	case stdinErr != nil:
+		return stdinErr
+	case waitErr != nil:
+		return fmt.Errorf("bzip2 compression failed: %v", waitErr)
+	default:
+		return fileErr
+	}
+}
+
+// writeTarEntry writes one file's header and content to a tar stream,
+// shared by the plain, gzip and bzip2 tar writers.
+func writeTarEntry(tw *tar.Writer, rootPath string, fileInfo *FileInfo) error {
+	name, err := archivePath(rootPath, fileInfo)
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Name: name,
+		Mode: 0644,
+		Size: int64(len(fileInfo.Content)),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err = tw.Write([]byte(fileInfo.Content))
+	return err
 }
 
 // Count the number of tokens (words) in a text
@@ -307,7 +1024,7 @@ func countTokens(text string) int {
 	words := strings.FieldsFunc(text, func(r rune) bool {
 		return unicode.IsSpace(r) || unicode.IsPunct(r)
 	})
-	
+
 	// Filter empty tokens
 	var validWords []string
 	for _, word := range words {
@@ -315,12 +1032,297 @@ func countTokens(text string) int {
 			validWords = append(validWords, word)
 		}
 	}
-	
+
 	return len(validWords)
 }
 
+// Tokenizer estimates how many LLM tokens a piece of text would consume.
+// Different backends trade accuracy against needing no external data.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// newTokenizer builds the Tokenizer selected by -tokenizer.
+func newTokenizer(name, vocabPath string) (Tokenizer, error) {
+	switch name {
+	case "", "words":
+		return wordsTokenizer{}, nil
+	case "chars":
+		return charsTokenizer{}, nil
+	case "bytes":
+		return bytesTokenizer{}, nil
+	case "tiktoken-cl100k", "tiktoken-o200k":
+		return newBPETokenizer(vocabPath)
+	default:
+		return nil, fmt.Errorf("unknown tokenizer %q (expected words, chars, bytes, tiktoken-cl100k or tiktoken-o200k)", name)
+	}
+}
+
+// wordsTokenizer is the original heuristic: split on whitespace/punctuation.
+type wordsTokenizer struct{}
+
+func (wordsTokenizer) CountTokens(text string) int {
+	return countTokens(text)
+}
+
+// charsTokenizer counts one token per Unicode code point.
+type charsTokenizer struct{}
+
+func (charsTokenizer) CountTokens(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// bytesTokenizer applies the common "~4 bytes per token" rule of thumb for
+// ballparking a token budget without running a real tokenizer.
+type bytesTokenizer struct{}
+
+func (bytesTokenizer) CountTokens(text string) int {
+	if len(text) == 0 {
+		return 0
+	}
+	return (len(text) + 3) / 4
+}
+
+// preTokenPattern stands in for the real tiktoken pre-tokenizer regex: the
+// cl100k/o200k patterns rely on lookaheads that Go's RE2-based regexp
+// engine can't express, so this instead splits runs of letters, runs of
+// digits, runs of whitespace, and runs of other characters.
+var preTokenPattern = regexp.MustCompile(`[\p{L}]+|[\p{N}]+|[^\s\p{L}\p{N}]+|\s+`)
+
+// bpeTokenizer implements the standard byte-pair-encoding merge loop: each
+// pre-token starts as a sequence of single-byte symbols, and the lowest-rank
+// adjacent pair found in the merge table is merged repeatedly until no
+// mergeable pair remains. ranks comes from a merges file (one "left right"
+// pair per line, in merge-priority order) pointed to by -tokenizer-vocab;
+// this repo ships no bundled vocabulary, so one must be supplied.
+type bpeTokenizer struct {
+	ranks map[string]int
+}
+
+func newBPETokenizer(vocabPath string) (*bpeTokenizer, error) {
+	if vocabPath == "" {
+		return nil, fmt.Errorf("tiktoken tokenizers require -tokenizer-vocab <merges file>")
+	}
+
+	data, err := os.ReadFile(vocabPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read tokenizer vocab %s: %v", vocabPath, err)
+	}
+
+	ranks := make(map[string]int)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Fields(line)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid merge rule on line %d of %s: %q", i+1, vocabPath, line)
+		}
+		ranks[parts[0]+" "+parts[1]] = i
+	}
+
+	return &bpeTokenizer{ranks: ranks}, nil
+}
+
+func (t *bpeTokenizer) CountTokens(text string) int {
+	total := 0
+	for _, preToken := range preTokenPattern.FindAllString(text, -1) {
+		total += len(t.merge(preToken))
+	}
+	return total
+}
+
+// merge runs the BPE merge loop on a single pre-token and returns its final
+// symbols, one per emitted token.
+func (t *bpeTokenizer) merge(preToken string) []string {
+	symbols := make([]string, 0, len(preToken))
+	for _, b := range []byte(preToken) {
+		symbols = append(symbols, string([]byte{b}))
+	}
+
+	for {
+		bestRank := -1
+		bestIndex := -1
+		for i := 0; i < len(symbols)-1; i++ {
+			rank, ok := t.ranks[symbols[i]+" "+symbols[i+1]]
+			if ok && (bestRank == -1 || rank < bestRank) {
+				bestRank = rank
+				bestIndex = i
+			}
+		}
+
+		if bestIndex == -1 {
+			break
+		}
+
+		merged := symbols[bestIndex] + symbols[bestIndex+1]
+		symbols = append(symbols[:bestIndex], append([]string{merged}, symbols[bestIndex+2:]...)...)
+	}
+
+	return symbols
+}
+
+// IndexEntry records one packed file as of a given run, so a later run can
+// tell whether it changed without re-reading and re-hashing its content.
+type IndexEntry struct {
+	Path    string    `json:"path"` // relative to rootDir, slash-separated
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash"` // sha256 of the file content, hex-encoded
+	Tokens  int       `json:"tokens"`
+}
+
+// Index is the on-disk shape of a .packfiles-index.json file.
+type Index struct {
+	GeneratedAt time.Time    `json:"generated_at"`
+	Files       []IndexEntry `json:"files"`
+}
+
+// IndexDiff summarizes how the current run's files differ from the
+// previous index, by path.
+type IndexDiff struct {
+	Added    []string
+	Modified []string
+	Removed  []string
+}
+
+// loadIndex reads an index file written by a previous run. A missing file
+// is not an error: it just means there is nothing to compare against yet.
+func loadIndex(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("unable to parse index %s: %v", path, err)
+	}
+
+	return &index, nil
+}
+
+// saveIndex writes index as pretty-printed JSON to path.
+func saveIndex(path string, index *Index) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// updateIndex tags each file with a content hash and token count - reused
+// from options.sinceFile's index when its size and mtime didn't change, so
+// unchanged files skip re-hashing and re-tokenizing - then writes the
+// resulting index to options.indexFile and returns a diff against the
+// previous index (nil if options.sinceFile wasn't set).
+func updateIndex(files []*FileInfo, options Options) (*IndexDiff, error) {
+	var previous *Index
+	if options.sinceFile != "" {
+		var err error
+		previous, err = loadIndex(options.sinceFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	previousByPath := make(map[string]IndexEntry)
+	if previous != nil {
+		for _, entry := range previous.Files {
+			previousByPath[entry.Path] = entry
+		}
+	}
+
+	rootPath, err := resolveRootPath(options.rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(files))
+	index := &Index{GeneratedAt: time.Now(), Files: make([]IndexEntry, 0, len(files))}
+	var diff *IndexDiff
+	if previous != nil {
+		diff = &IndexDiff{}
+	}
+
+	for _, fileInfo := range files {
+		relPath, err := archivePath(rootPath, fileInfo)
+		if err != nil {
+			return nil, err
+		}
+		seen[relPath] = true
+
+		if prevEntry, ok := previousByPath[relPath]; ok &&
+			prevEntry.Size == fileInfo.Size && prevEntry.ModTime.Equal(fileInfo.ModTime) {
+			// Unchanged: reuse the cached hash and token count instead of
+			// re-hashing and re-tokenizing the content.
+			fileInfo.Hash = prevEntry.Hash
+			fileInfo.Tokens = prevEntry.Tokens
+		} else {
+			fileInfo.Hash = hashContent(fileInfo.Content)
+			fileInfo.Tokens = options.Tokenizer.CountTokens(fileInfo.Content)
+			if diff != nil {
+				if ok {
+					diff.Modified = append(diff.Modified, relPath)
+				} else {
+					diff.Added = append(diff.Added, relPath)
+				}
+			}
+		}
+
+		index.Files = append(index.Files, IndexEntry{
+			Path:    relPath,
+			Size:    fileInfo.Size,
+			ModTime: fileInfo.ModTime,
+			Hash:    fileInfo.Hash,
+			Tokens:  fileInfo.Tokens,
+		})
+	}
+
+	if diff != nil {
+		for path := range previousByPath {
+			if !seen[path] {
+				diff.Removed = append(diff.Removed, path)
+			}
+		}
+		sort.Strings(diff.Added)
+		sort.Strings(diff.Modified)
+		sort.Strings(diff.Removed)
+	}
+
+	if options.indexFile != "" {
+		if err := saveIndex(options.indexFile, index); err != nil {
+			return nil, err
+		}
+	}
+
+	return diff, nil
+}
+
+// sortedKeys returns the keys of m in sorted order, for deterministic output.
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hashContent returns the hex-encoded sha256 of content.
+func hashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
 // Generate summary file with statistics
-func generateSummaryFile(stats Statistics, options Options) error {
+func generateSummaryFile(stats Statistics, options Options, diff *IndexDiff) error {
 	// Create summary file
 	summaryFile, err := os.Create(options.summaryFile)
 	if err != nil {
@@ -332,11 +1334,289 @@ func generateSummaryFile(stats Statistics, options Options) error {
 	fmt.Fprintln(summaryFile, "Statistics Summary")
 	fmt.Fprintln(summaryFile, "=================")
 	fmt.Fprintf(summaryFile, "Total files processed: %d\n", stats.TotalFiles)
-	fmt.Fprintf(summaryFile, "Total file size: %.2f KB (%.2f MB)\n", 
+	fmt.Fprintf(summaryFile, "Total file size: %.2f KB (%.2f MB)\n",
 		float64(stats.TotalSize)/1024.0, float64(stats.TotalSize)/(1024.0*1024.0))
 	fmt.Fprintf(summaryFile, "Average file size: %.2f KB\n", stats.AverageFileSize/1024.0)
 	fmt.Fprintf(summaryFile, "Total tokens: %d\n", stats.TotalTokens)
 	fmt.Fprintf(summaryFile, "Average tokens per file: %.2f\n", stats.AverageTokens)
 
+	if len(stats.TokensByExt) > 0 {
+		fmt.Fprintln(summaryFile)
+		fmt.Fprintln(summaryFile, "Tokens by extension")
+		fmt.Fprintln(summaryFile, "=================")
+		for _, ext := range sortedKeys(stats.TokensByExt) {
+			fmt.Fprintf(summaryFile, "  %s: %d\n", ext, stats.TokensByExt[ext])
+		}
+	}
+
+	if len(stats.TokensByFile) > 0 {
+		fmt.Fprintln(summaryFile)
+		fmt.Fprintln(summaryFile, "Tokens by file")
+		fmt.Fprintln(summaryFile, "=================")
+		for _, path := range sortedKeys(stats.TokensByFile) {
+			fmt.Fprintf(summaryFile, "  %s: %d\n", path, stats.TokensByFile[path])
+		}
+	}
+
+	if diff != nil {
+		fmt.Fprintln(summaryFile)
+		fmt.Fprintln(summaryFile, "Index Diff (vs previous run)")
+		fmt.Fprintln(summaryFile, "=================")
+		fmt.Fprintf(summaryFile, "Added: %d\n", len(diff.Added))
+		for _, path := range diff.Added {
+			fmt.Fprintf(summaryFile, "  + %s\n", path)
+		}
+		fmt.Fprintf(summaryFile, "Modified: %d\n", len(diff.Modified))
+		for _, path := range diff.Modified {
+			fmt.Fprintf(summaryFile, "  ~ %s\n", path)
+		}
+		fmt.Fprintf(summaryFile, "Removed: %d\n", len(diff.Removed))
+		for _, path := range diff.Removed {
+			fmt.Fprintf(summaryFile, "  - %s\n", path)
+		}
+	}
+
+	return nil
+}
+
+// packServer serves a live, browsable view of what a pack run would
+// produce: GET /structure, GET /files/<path>, GET /summary (JSON) and
+// GET /download.{zip,tar.gz}. A background poller watches rootDir for
+// changes and marks the cache dirty, so the next request rescans lazily
+// instead of serving a stale tree.
+type packServer struct {
+	options  Options
+	rootPath string
+
+	mu    sync.Mutex
+	files []*FileInfo
+	stats Statistics
+	dirty bool
+}
+
+// serve starts the HTTP server and blocks until it exits.
+func serve(options Options) error {
+	rootPath, err := resolveRootPath(options.rootDir)
+	if err != nil {
+		return err
+	}
+
+	s := &packServer{options: options, rootPath: rootPath}
+	if err := s.rescan(); err != nil {
+		return err
+	}
+	go s.watch()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/structure", s.handleStructure)
+	mux.HandleFunc("/files/", s.handleFile)
+	mux.HandleFunc("/summary", s.handleSummary)
+	mux.HandleFunc("/download.zip", s.handleDownloadZip)
+	mux.HandleFunc("/download.tar.gz", s.handleDownloadTarGz)
+
+	fmt.Printf("Serving %s on %s\n", options.rootDir, options.serveAddr)
+	fmt.Println("Routes: /structure, /files/<path>, /summary, /download.zip, /download.tar.gz")
+
+	return http.ListenAndServe(options.serveAddr, mux)
+}
+
+// rescan re-walks the tree and refreshes the cached files/stats.
+func (s *packServer) rescan() error {
+	files, err := walkDirectory(s.options)
+	if err != nil {
+		return err
+	}
+
+	for _, fileInfo := range files {
+		fileInfo.Tokens = s.options.Tokenizer.CountTokens(fileInfo.Content)
+	}
+
+	s.mu.Lock()
+	s.files = files
+	s.stats = computeStatistics(files)
+	s.dirty = false
+	s.mu.Unlock()
+
 	return nil
+}
+
+// snapshot returns the current files/stats, rescanning first if the
+// background watcher has flagged the tree as changed since the last scan.
+func (s *packServer) snapshot() ([]*FileInfo, Statistics) {
+	s.mu.Lock()
+	dirty := s.dirty
+	s.mu.Unlock()
+
+	if dirty {
+		if err := s.rescan(); err != nil {
+			fmt.Printf("Error rescanning %s: %v\n", s.options.rootDir, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.files, s.stats
+}
+
+// watch polls rootDir once a second and flags the cache dirty on any
+// change. The standard library has no portable recursive filesystem-watch
+// API, so this stands in for one: it only stats files, never reads their
+// content, so it stays cheap even on large trees.
+func (s *packServer) watch() {
+	lastSignature := s.signature()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		sig := s.signature()
+		if sig == lastSignature {
+			continue
+		}
+		lastSignature = sig
+
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+	}
+}
+
+// signature is a cheap fingerprint of every file's size and mtime under
+// rootDir, used to detect changes without re-reading any content.
+func (s *packServer) signature() string {
+	var sig strings.Builder
+
+	filepath.Walk(s.options.rootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		fmt.Fprintf(&sig, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+
+	return sig.String()
+}
+
+func (s *packServer) handleStructure(w http.ResponseWriter, r *http.Request) {
+	files, _ := s.snapshot()
+
+	tree, err := buildStructureTree(files, s.options)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, tree)
+}
+
+func (s *packServer) handleSummary(w http.ResponseWriter, r *http.Request) {
+	_, stats := s.snapshot()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+func (s *packServer) handleFile(w http.ResponseWriter, r *http.Request) {
+	requested := strings.TrimPrefix(r.URL.Path, "/files/")
+	files, _ := s.snapshot()
+
+	for _, fileInfo := range files {
+		relPath, err := archivePath(s.rootPath, fileInfo)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if relPath == requested {
+			w.Header().Set("Content-Type", contentTypeFor(relPath))
+			io.WriteString(w, fileInfo.Content)
+			return
+		}
+	}
+
+	http.NotFound(w, r)
+}
+
+func (s *packServer) handleDownloadZip(w http.ResponseWriter, r *http.Request) {
+	files, _ := s.snapshot()
+
+	// Build the archive into memory first so a failure can still be
+	// reported as an error status instead of a truncated 200 - headers and
+	// the status line can't be taken back once written to w.
+	var buf bytes.Buffer
+	if err := writeZipArchive(&buf, files, s.rootPath); err != nil {
+		http.Error(w, fmt.Sprintf("failed to build zip archive: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=pack.zip")
+	w.Write(buf.Bytes())
+}
+
+func (s *packServer) handleDownloadTarGz(w http.ResponseWriter, r *http.Request) {
+	files, _ := s.snapshot()
+
+	var buf bytes.Buffer
+	if err := writeTarGzArchive(&buf, files, s.rootPath); err != nil {
+		http.Error(w, fmt.Sprintf("failed to build tar.gz archive: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", "attachment; filename=pack.tar.gz")
+	w.Write(buf.Bytes())
+}
+
+// contentTypeFor gives /files/<path> a syntax-hint-aware content type via a
+// best-effort mime.TypeByExtension lookup, falling back to plain text so
+// unrecognized source files still render inline instead of downloading.
+func contentTypeFor(path string) string {
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// writeZipArchive packs files into a zip archive written directly to w, for
+// the on-demand "/download.zip" route.
+func writeZipArchive(w io.Writer, files []*FileInfo, rootPath string) error {
+	zw := zip.NewWriter(w)
+
+	for _, fileInfo := range files {
+		name, err := archivePath(rootPath, fileInfo)
+		if err != nil {
+			return err
+		}
+
+		entry, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+
+		if _, err := entry.Write([]byte(fileInfo.Content)); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// writeTarGzArchive packs files into a gzip-compressed tar archive written
+// directly to w, for the on-demand "/download.tar.gz" route.
+func writeTarGzArchive(w io.Writer, files []*FileInfo, rootPath string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, fileInfo := range files {
+		if err := writeTarEntry(tw, rootPath, fileInfo); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
 }
\ No newline at end of file
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMatchPatternDoubleStarZeroDirs(t *testing.T) {
+	cases := []struct {
+		pattern string
+		relPath string
+		matched bool
+	}{
+		{"docs/**/*.md", "docs/index.md", true},
+		{"docs/**/*.md", "docs/sub/guide.md", true},
+		{"docs/**/*.md", "docs/sub/deeper/guide.md", true},
+		{"docs/**/*.md", "other.md", false},
+		{"vendor/**", "vendor", true},
+		{"vendor/**", "vendor/a/b/c.go", true},
+	}
+
+	for _, c := range cases {
+		matched, _ := matchPattern(c.pattern, c.relPath)
+		if matched != c.matched {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", c.pattern, c.relPath, matched, c.matched)
+		}
+	}
+}
+
+func TestFormatFromExtension(t *testing.T) {
+	cases := []struct {
+		name string
+		want string
+	}{
+		{"out.zip", "zip"},
+		{"out.tar", "tar"},
+		{"out.tar.gz", "tar.gz"},
+		{"out.gz", "tar.gz"},
+		{"out.tar.bz2", "tar.bz2"},
+		{"out.txt", "text"},
+	}
+
+	for _, c := range cases {
+		if got := formatFromExtension(c.name); got != c.want {
+			t.Errorf("formatFromExtension(%q) = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestNewContentWriterUnsupportedFormat(t *testing.T) {
+	dir := t.TempDir()
+	options := Options{
+		rootDir:     dir,
+		contentFile: filepath.Join(dir, "out.bin"),
+		format:      "rar",
+	}
+
+	if _, err := newContentWriter(options); err == nil {
+		t.Error("newContentWriter with format \"rar\" = nil error, want an error")
+	}
+}
+
+func TestShouldPruneDir(t *testing.T) {
+	patterns := []string{"docs/**/*.md"}
+
+	if shouldPruneDir("docs", patterns) {
+		t.Error("shouldPruneDir(\"docs\", ...) = true, want false: docs/index.md could still match")
+	}
+	if !shouldPruneDir("other", patterns) {
+		t.Error("shouldPruneDir(\"other\", ...) = false, want true: nothing under other/ can match")
+	}
+}
+
+func TestNewTokenizerDispatch(t *testing.T) {
+	cases := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"", false},
+		{"words", false},
+		{"chars", false},
+		{"bytes", false},
+		{"tiktoken-cl100k", true}, // no -tokenizer-vocab given
+		{"bogus", true},
+	}
+
+	for _, c := range cases {
+		_, err := newTokenizer(c.name, "")
+		if (err != nil) != c.wantErr {
+			t.Errorf("newTokenizer(%q, \"\") error = %v, wantErr %v", c.name, err, c.wantErr)
+		}
+	}
+}
+
+func TestBPETokenizerSingleByteSymbols(t *testing.T) {
+	dir := t.TempDir()
+	vocabPath := filepath.Join(dir, "merges.txt")
+	if err := os.WriteFile(vocabPath, []byte("# empty merge table\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err := newBPETokenizer(vocabPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With no merges, each raw byte of a non-ASCII rune stays its own
+	// token - it must not be re-encoded into a wider UTF-8 sequence first.
+	text := "café"
+	want := len([]byte(text))
+	if got := tok.CountTokens(text); got != want {
+		t.Errorf("CountTokens(%q) = %d, want %d (one token per raw byte)", text, got, want)
+	}
+}
+
+func TestBPETokenizerMerge(t *testing.T) {
+	dir := t.TempDir()
+	vocabPath := filepath.Join(dir, "merges.txt")
+	if err := os.WriteFile(vocabPath, []byte("a b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err := newBPETokenizer(vocabPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := tok.CountTokens("ab"); got != 1 {
+		t.Errorf("CountTokens(\"ab\") = %d, want 1 after merging \"a b\"", got)
+	}
+}
+
+func TestUpdateIndexCacheReuseAndDiff(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Now()
+
+	// "a.txt" is unchanged (same size/mtime as the previous index), so its
+	// hash and token count must be reused from the cache rather than
+	// recomputed from fileInfo.Content. "b.txt" changed size, so it must
+	// be re-hashed/re-tokenized and reported as modified. "c.txt" is gone
+	// from the current run and must show up as removed. "d.txt" is new.
+	previous := &Index{
+		Files: []IndexEntry{
+			{Path: "a.txt", Size: 5, ModTime: modTime, Hash: "cached-hash", Tokens: 999},
+			{Path: "b.txt", Size: 3, ModTime: modTime, Hash: "stale-hash", Tokens: 1},
+			{Path: "c.txt", Size: 1, ModTime: modTime, Hash: "gone-hash", Tokens: 1},
+		},
+	}
+	sinceFile := filepath.Join(dir, "since.json")
+	if err := saveIndex(sinceFile, previous); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []*FileInfo{
+		{Path: filepath.Join(dir, "a.txt"), Size: 5, ModTime: modTime, Content: "hello"},
+		{Path: filepath.Join(dir, "b.txt"), Size: 9, ModTime: modTime, Content: "new content"},
+		{Path: filepath.Join(dir, "d.txt"), Size: 2, ModTime: modTime, Content: "hi"},
+	}
+
+	options := Options{
+		rootDir:   dir,
+		indexFile: filepath.Join(dir, "index.json"),
+		sinceFile: sinceFile,
+		Tokenizer: wordsTokenizer{},
+	}
+
+	diff, err := updateIndex(files, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if files[0].Hash != "cached-hash" || files[0].Tokens != 999 {
+		t.Errorf("a.txt: Hash=%q Tokens=%d, want cached values reused from the index", files[0].Hash, files[0].Tokens)
+	}
+	if files[1].Hash == "stale-hash" {
+		t.Error("b.txt: Hash was reused from the cache despite its size changing")
+	}
+
+	if diff == nil {
+		t.Fatal("diff = nil, want a diff since sinceFile was set")
+	}
+	if len(diff.Added) != 1 || diff.Added[0] != "d.txt" {
+		t.Errorf("diff.Added = %v, want [d.txt]", diff.Added)
+	}
+	if len(diff.Modified) != 1 || diff.Modified[0] != "b.txt" {
+		t.Errorf("diff.Modified = %v, want [b.txt]", diff.Modified)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != "c.txt" {
+		t.Errorf("diff.Removed = %v, want [c.txt]", diff.Removed)
+	}
+}
+
+// TestPackServerRoutesWithRelativeRootDir exercises the server against the
+// tool's own default "-dir ." configuration, where rootPath (resolved to an
+// absolute cwd) and each FileInfo.Path must stay in the same coordinate
+// space or every route downstream of archivePath/filepath.Rel breaks.
+func TestPackServerRoutesWithRelativeRootDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docs", "index.md"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	origWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(origWd)
+
+	options := Options{rootDir: ".", includeExts: []string{".md"}, Tokenizer: wordsTokenizer{}}
+	rootPath, err := resolveRootPath(options.rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &packServer{options: options, rootPath: rootPath}
+	if err := s.rescan(); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleStructure(rec, httptest.NewRequest("GET", "/structure", nil))
+	if rec.Code != 200 {
+		t.Errorf("GET /structure = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleFile(rec, httptest.NewRequest("GET", "/files/docs/index.md", nil))
+	if rec.Code != 200 || rec.Body.String() != "hello" {
+		t.Errorf("GET /files/docs/index.md = %d %q, want 200 \"hello\"", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleDownloadZip(rec, httptest.NewRequest("GET", "/download.zip", nil))
+	if rec.Code != 200 || rec.Body.Len() == 0 {
+		t.Errorf("GET /download.zip = %d with %d bytes, want 200 with a non-empty body", rec.Code, rec.Body.Len())
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleDownloadTarGz(rec, httptest.NewRequest("GET", "/download.tar.gz", nil))
+	if rec.Code != 200 || rec.Body.Len() == 0 {
+		t.Errorf("GET /download.tar.gz = %d with %d bytes, want 200 with a non-empty body", rec.Code, rec.Body.Len())
+	}
+}